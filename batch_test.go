@@ -0,0 +1,152 @@
+package minkv
+
+import (
+	"testing"
+)
+
+func TestBatchWrite(t *testing.T) {
+	store := setupKV(t)
+	defer cleanupKV(t, store)
+
+	batch := NewBatch()
+	batch.Put([]byte("key1"), []byte("value1"))
+	batch.Put([]byte("key2"), []byte("value2"))
+
+	if err := store.Write(batch, true); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	for key, want := range map[string]string{"key1": "value1", "key2": "value2"} {
+		got, err := store.Get([]byte(key))
+		if err != nil {
+			t.Fatalf("Get(%s) failed: %v", key, err)
+		}
+		if string(got) != want {
+			t.Errorf("Get(%s) = %s, want %s", key, got, want)
+		}
+	}
+}
+
+func TestBatchDeleteInSameBatch(t *testing.T) {
+	store := setupKV(t)
+	defer cleanupKV(t, store)
+
+	if err := store.Put([]byte("key1"), []byte("value1")); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	batch := NewBatch()
+	batch.Put([]byte("key2"), []byte("value2"))
+	batch.Delete([]byte("key1"))
+
+	if err := store.Write(batch, false); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	if _, err := store.Get([]byte("key1")); err == nil {
+		t.Error("expected error for deleted key1, got nil")
+	}
+
+	value, err := store.Get([]byte("key2"))
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if string(value) != "value2" {
+		t.Errorf("Get(key2) = %s, want value2", value)
+	}
+}
+
+func TestWriteRejectsEmptyKey(t *testing.T) {
+	store := setupKV(t)
+	defer cleanupKV(t, store)
+
+	batch := NewBatch()
+	batch.Put([]byte("key1"), []byte("value1"))
+	batch.Put([]byte(""), []byte("value2"))
+
+	if err := store.Write(batch, false); err == nil {
+		t.Fatal("expected Write to reject a batch containing an empty key")
+	}
+
+	// the whole batch must be rejected, not just the bad entry: key1 should
+	// not have been written either.
+	if _, err := store.Get([]byte("key1")); err == nil {
+		t.Error("expected key1 to be rejected along with the empty key in the same batch")
+	}
+}
+
+func TestBuildIndexDropsTruncatedTrailingBatch(t *testing.T) {
+	store := setupKV(t)
+
+	if err := store.Put([]byte("key1"), []byte("value1")); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	batch := NewBatch()
+	batch.Put([]byte("key2"), []byte("value2"))
+	if err := store.Write(batch, true); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	info, err := store.file.Stat()
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+
+	// simulate a crash mid-write: truncate off the tail of the second batch
+	if err := store.file.Truncate(info.Size() - 3); err != nil {
+		t.Fatalf("Truncate failed: %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	reopened, err := Open("test.db")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer cleanupKV(t, reopened)
+
+	value, err := reopened.Get([]byte("key1"))
+	if err != nil {
+		t.Fatalf("Get(key1) failed: %v", err)
+	}
+	if string(value) != "value1" {
+		t.Errorf("Get(key1) = %s, want value1", value)
+	}
+
+	if _, err := reopened.Get([]byte("key2")); err == nil {
+		t.Error("expected the torn batch containing key2 to be rolled back, got nil error")
+	}
+}
+
+func TestBatchReplay(t *testing.T) {
+	batch := NewBatch()
+	batch.Put([]byte("key1"), []byte("value1"))
+	batch.Delete([]byte("key2"))
+
+	payload := batch.encode(1, 123)
+
+	replay := NewBatchReplay(payload)
+
+	if !replay.Next() {
+		t.Fatalf("expected first operation, got none: %v", replay.Err())
+	}
+	if string(replay.Key()) != "key1" || string(replay.Value()) != "value1" || replay.Tombstone() {
+		t.Errorf("unexpected first operation: key=%s value=%s tombstone=%v", replay.Key(), replay.Value(), replay.Tombstone())
+	}
+
+	if !replay.Next() {
+		t.Fatalf("expected second operation, got none: %v", replay.Err())
+	}
+	if string(replay.Key()) != "key2" || !replay.Tombstone() {
+		t.Errorf("unexpected second operation: key=%s tombstone=%v", replay.Key(), replay.Tombstone())
+	}
+
+	if replay.Next() {
+		t.Error("expected no more operations")
+	}
+	if replay.Err() != nil {
+		t.Errorf("unexpected error: %v", replay.Err())
+	}
+}
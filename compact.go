@@ -0,0 +1,210 @@
+package minkv
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"math"
+	"os"
+	"time"
+)
+
+const compactSuffix = ".compact"
+
+// Compact rewrites the log, dropping tombstones and superseded versions of
+// every key, similarly to Bitcask's merge process. A key's versions still
+// reachable by an open Snapshot are preserved; everything else is collapsed
+// down to just its current value.
+//
+// Compact holds the store's lock for its whole duration, so concurrent
+// Put/Delete/Get calls block until it finishes. Run it via
+// StartAutoCompaction, or during a maintenance window, rather than on a
+// latency-sensitive path.
+func (s *Store) Compact() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.compactLocked()
+}
+
+func (s *Store) compactLocked() error {
+	oldestSnap := s.oldestOpenSnapshotSeq()
+
+	type survivor struct {
+		key     string
+		entries []indexEntry
+	}
+
+	var survivors []survivor
+	recordCount := 0
+	for key, versions := range s.index {
+		kept := versionsToKeep(versions, oldestSnap)
+		if len(kept) == 0 {
+			continue
+		}
+		survivors = append(survivors, survivor{key: key, entries: kept})
+		recordCount += len(kept)
+	}
+
+	payload := make([]byte, 0, recordCount*headerSize)
+	newIndex := make(map[string][]indexEntry, len(survivors))
+	offset := int64(batchHeaderSize)
+	for _, sv := range survivors {
+		entries := make([]indexEntry, 0, len(sv.entries))
+		for _, v := range sv.entries {
+			record, err := s.readRecord(v.offset)
+			if err != nil {
+				return fmt.Errorf("failed to read record for key %q during compaction: %w", sv.key, err)
+			}
+
+			buf := make([]byte, recordSize(record))
+			encodeRecord(buf, record)
+			payload = append(payload, buf...)
+
+			entries = append(entries, indexEntry{seq: v.seq, offset: offset, tombstone: v.tombstone})
+			offset += int64(len(buf))
+		}
+		newIndex[sv.key] = entries
+	}
+
+	compactPath := s.filename + compactSuffix
+	compactFile, err := os.OpenFile(compactPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return fmt.Errorf("failed to create compaction file: %w", err)
+	}
+
+	header := make([]byte, batchHeaderSize)
+	binary.BigEndian.PutUint64(header[0:8], 0)
+	binary.BigEndian.PutUint32(header[8:12], uint32(recordCount))
+	binary.BigEndian.PutUint32(header[12:16], crc32.Checksum(payload, crc32cTable))
+
+	if _, err := compactFile.Write(header); err != nil {
+		compactFile.Close()
+		os.Remove(compactPath)
+		return fmt.Errorf("failed to write compaction batch header: %w", err)
+	}
+	if _, err := compactFile.Write(payload); err != nil {
+		compactFile.Close()
+		os.Remove(compactPath)
+		return fmt.Errorf("failed to write compacted records: %w", err)
+	}
+	if err := compactFile.Sync(); err != nil {
+		compactFile.Close()
+		os.Remove(compactPath)
+		return fmt.Errorf("failed to sync compaction file: %w", err)
+	}
+	if err := compactFile.Close(); err != nil {
+		os.Remove(compactPath)
+		return fmt.Errorf("failed to close compaction file: %w", err)
+	}
+
+	if err := os.Rename(compactPath, s.filename); err != nil {
+		os.Remove(compactPath)
+		return fmt.Errorf("failed to swap in compacted file: %w", err)
+	}
+
+	if err := s.file.Close(); err != nil {
+		return fmt.Errorf("failed to close old file: %w", err)
+	}
+	file, err := os.OpenFile(s.filename, os.O_RDWR|os.O_CREATE, 0666)
+	if err != nil {
+		return fmt.Errorf("failed to reopen compacted file: %w", err)
+	}
+	s.file = file
+	s.index = newIndex
+	s.keys = sortedKeys(newIndex)
+
+	// the hint file's offsets point into the log we just replaced, so it
+	// must be rebuilt from the new index rather than carried over.
+	if err := s.rewriteHint(); err != nil {
+		return fmt.Errorf("failed to rewrite hint file after compaction: %w", err)
+	}
+	return nil
+}
+
+// versionsToKeep returns the suffix of versions that must survive
+// compaction: everything from the version that was current as of
+// oldestOpenSnap onward, so any snapshot still open can be satisfied, plus
+// always the latest version. If latest is a tombstone and no open snapshot
+// needs the key's history, nothing is kept and the key disappears entirely.
+func versionsToKeep(versions []indexEntry, oldestOpenSnap uint64) []indexEntry {
+	if len(versions) == 0 {
+		return nil
+	}
+
+	if oldestOpenSnap == math.MaxUint64 {
+		latest := versions[len(versions)-1]
+		if latest.tombstone {
+			return nil
+		}
+		return versions[len(versions)-1:]
+	}
+
+	_, idx, ok := versionAtIndex(versions, oldestOpenSnap)
+	if !ok {
+		// no version predates the oldest open snapshot; every version here
+		// was written after it, so none of them can be dropped yet.
+		return versions
+	}
+	return versions[idx:]
+}
+
+// StartAutoCompaction launches a background goroutine that checks the log's
+// garbage ratio every interval and runs Compact whenever it meets or exceeds
+// ratio (a value in [0, 1)). It returns a function that stops the
+// goroutine; callers should call it before closing the store.
+func (s *Store) StartAutoCompaction(ratio float64, interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if s.garbageRatio() >= ratio {
+					_ = s.Compact()
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
+
+// garbageRatio estimates the fraction of the log made up of superseded
+// versions and tombstones, i.e. bytes that compaction would reclaim.
+func (s *Store) garbageRatio() float64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	info, err := s.file.Stat()
+	if err != nil {
+		return 0
+	}
+	total := info.Size()
+	if total == 0 {
+		return 0
+	}
+
+	var live int64
+	for _, versions := range s.index {
+		latest := versions[len(versions)-1]
+		if latest.tombstone {
+			continue
+		}
+		record, err := s.readRecord(latest.offset)
+		if err != nil {
+			continue
+		}
+		live += int64(recordSize(record))
+	}
+
+	if live >= total {
+		return 0
+	}
+	return float64(total-live) / float64(total)
+}
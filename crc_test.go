@@ -0,0 +1,152 @@
+package minkv
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestDetectsCorruptedRecordOnOpen(t *testing.T) {
+	store := setupKV(t)
+
+	if err := store.Put([]byte("key1"), []byte("value1")); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	// flip the last byte of the value to corrupt the record without
+	// changing its length, so the corruption is only caught by the CRC.
+	data, err := os.ReadFile("test.db")
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	data[len(data)-1] ^= 0xFF
+	if err := os.WriteFile("test.db", data, 0666); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	reopened, err := Open("test.db")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer cleanupKV(t, reopened)
+
+	if _, err := reopened.Get([]byte("key1")); err == nil {
+		t.Error("expected the corrupted record to have been dropped, got nil error")
+	}
+}
+
+func TestStrictOpenReturnsErrCorrupted(t *testing.T) {
+	store := setupKV(t)
+
+	if err := store.Put([]byte("key1"), []byte("value1")); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	defer os.Remove("test.db")
+	defer os.Remove("test.db.hint")
+
+	data, err := os.ReadFile("test.db")
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	data[len(data)-1] ^= 0xFF
+	if err := os.WriteFile("test.db", data, 0666); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	_, err = Open("test.db", Options{Strict: true})
+	if err == nil {
+		t.Fatal("expected Open to fail in strict mode, got nil error")
+	}
+
+	var corrupted *ErrCorrupted
+	if !errors.As(err, &corrupted) {
+		t.Fatalf("expected *ErrCorrupted, got %T: %v", err, err)
+	}
+	if corrupted.Offset != 0 {
+		t.Errorf("Offset = %d, want 0", corrupted.Offset)
+	}
+}
+
+// TestCorruptedRecordLengthDoesNotOOM guards against a torn or corrupted
+// record header being trusted to size an allocation: keyLen/valueLen come
+// straight off disk, so a single flipped bit can claim a body of several
+// gigabytes. Open must reject it (or truncate it away) rather than trying
+// to allocate that much.
+func TestCorruptedRecordLengthDoesNotOOM(t *testing.T) {
+	store := setupKV(t)
+
+	if err := store.Put([]byte("key1"), []byte("value1")); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	defer os.Remove("test.db")
+	defer os.Remove("test.db.hint")
+
+	data, err := os.ReadFile("test.db")
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	// the record immediately follows the 16-byte batch header; its keyLen
+	// field is the 4 bytes at offset 12 within the record header.
+	keyLenOffset := batchHeaderSize + 12
+	binary.BigEndian.PutUint32(data[keyLenOffset:keyLenOffset+4], 0xFFFFFFF0)
+	if err := os.WriteFile("test.db", data, 0666); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	reopened, err := Open("test.db")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer cleanupKV(t, reopened)
+
+	if _, err := reopened.Get([]byte("key1")); err == nil {
+		t.Error("expected the corrupted record to have been dropped, got nil error")
+	}
+}
+
+// TestCorruptedBatchCountDoesNotOOM is the batch-header equivalent of
+// TestCorruptedRecordLengthDoesNotOOM: a torn or corrupted count field must
+// be bounded against the file's actual remaining size before it's trusted
+// to size the records slice.
+func TestCorruptedBatchCountDoesNotOOM(t *testing.T) {
+	store := setupKV(t)
+
+	if err := store.Put([]byte("key1"), []byte("value1")); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	defer os.Remove("test.db")
+	defer os.Remove("test.db.hint")
+
+	data, err := os.ReadFile("test.db")
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	// the batch header's count field is the 4 bytes at offset 8.
+	binary.BigEndian.PutUint32(data[8:12], 0xFFFFFFF0)
+	if err := os.WriteFile("test.db", data, 0666); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	reopened, err := Open("test.db")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer cleanupKV(t, reopened)
+
+	if _, err := reopened.Get([]byte("key1")); err == nil {
+		t.Error("expected the corrupted batch to have been dropped, got nil error")
+	}
+}
@@ -0,0 +1,24 @@
+package minkv
+
+import (
+	"errors"
+	"fmt"
+)
+
+// errChecksumMismatch is wrapped into the error returned by decodeRecord
+// when a record's CRC32C doesn't match its contents.
+var errChecksumMismatch = errors.New("checksum mismatch")
+
+// ErrCorrupted is returned by Open when it is opened with Options{Strict:
+// true} and finds a torn or corrupted trailing batch, instead of silently
+// truncating it away.
+type ErrCorrupted struct {
+	// Offset is the position in the log where the damaged batch begins.
+	Offset int64
+	// Reason describes what failed to validate.
+	Reason string
+}
+
+func (e *ErrCorrupted) Error() string {
+	return fmt.Sprintf("corrupted log at offset %d: %s", e.Offset, e.Reason)
+}
@@ -0,0 +1,197 @@
+package minkv
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"os"
+)
+
+// hintSuffix names the companion file that caches the index built from the
+// log, so Open can skip a full replay on a clean start. It is best-effort:
+// if it is missing, stale or corrupt, Open silently falls back to
+// buildIndex and heals it.
+const hintSuffix = ".hint"
+
+// hintEntryFixedSize is the size of a hint entry excluding its variable-length
+// key: key len (4 bytes) + offset (8 bytes) + seq (8 bytes) + tombstone (1
+// byte) + CRC32C (4 bytes).
+const hintEntryFixedSize = 4 + 8 + 8 + 1 + 4
+
+func hintPath(filename string) string {
+	return filename + hintSuffix
+}
+
+// encodeHintEntry serializes one key's current version as it should be
+// appended to the hint file: keyLen, key, offset, seq, tombstone, followed
+// by a CRC32C over everything before it, so a torn append is detectable and
+// can be dropped without corrupting the entries before it.
+func encodeHintEntry(key []byte, offset int64, seq uint64, tombstone bool) []byte {
+	buf := make([]byte, 4+len(key)+hintEntryFixedSize-4)
+
+	binary.BigEndian.PutUint32(buf[0:4], uint32(len(key)))
+	copy(buf[4:4+len(key)], key)
+
+	rest := buf[4+len(key):]
+	binary.BigEndian.PutUint64(rest[0:8], uint64(offset))
+	binary.BigEndian.PutUint64(rest[8:16], seq)
+	if tombstone {
+		rest[16] = 1
+	} else {
+		rest[16] = 0
+	}
+
+	crc := crc32.Checksum(buf[:4+len(key)+17], crc32cTable)
+	binary.BigEndian.PutUint32(rest[17:21], crc)
+
+	return buf
+}
+
+// decodeHintEntry parses a single hint entry from the start of buf,
+// returning the number of bytes it occupies. It returns an error if buf is
+// too short or the entry's CRC32C doesn't match.
+func decodeHintEntry(buf []byte) (key []byte, offset int64, seq uint64, tombstone bool, n int, err error) {
+	if len(buf) < 4 {
+		return nil, 0, 0, false, 0, io.ErrUnexpectedEOF
+	}
+	keyLen := binary.BigEndian.Uint32(buf[0:4])
+
+	total := 4 + int(keyLen) + hintEntryFixedSize - 4
+	if len(buf) < total {
+		return nil, 0, 0, false, 0, io.ErrUnexpectedEOF
+	}
+
+	rest := buf[4+int(keyLen):]
+	wantCRC := binary.BigEndian.Uint32(rest[17:21])
+	if gotCRC := crc32.Checksum(buf[:total-4], crc32cTable); gotCRC != wantCRC {
+		return nil, 0, 0, false, 0, errChecksumMismatch
+	}
+
+	key = buf[4 : 4+keyLen]
+	offset = int64(binary.BigEndian.Uint64(rest[0:8]))
+	seq = binary.BigEndian.Uint64(rest[8:16])
+	tombstone = rest[16] == 1
+	return key, offset, seq, tombstone, total, nil
+}
+
+// loadHint attempts to rebuild the index from the hint file instead of
+// replaying the whole log. It refuses the hint file (returning ok=false) if
+// it is missing, empty, older than the data file, or fails to parse
+// cleanly, so Open always falls back to a full, correct replay rather than
+// risking a stale index.
+func (s *Store) loadHint() (index map[string][]indexEntry, nextSeq uint64, ok bool) {
+	hintInfo, err := s.hintFile.Stat()
+	if err != nil || hintInfo.Size() == 0 {
+		return nil, 0, false
+	}
+
+	dataInfo, err := s.file.Stat()
+	if err != nil {
+		return nil, 0, false
+	}
+	if hintInfo.ModTime().Before(dataInfo.ModTime()) {
+		return nil, 0, false
+	}
+
+	data := make([]byte, hintInfo.Size())
+	if _, err := s.hintFile.ReadAt(data, 0); err != nil {
+		return nil, 0, false
+	}
+
+	index = make(map[string][]indexEntry)
+	var maxSeq uint64
+	offset := 0
+	for offset < len(data) {
+		key, recOffset, seq, tombstone, n, err := decodeHintEntry(data[offset:])
+		if err != nil {
+			// a torn or corrupt trailing entry means the hint file wasn't
+			// fully synced before the process died; it's simplest and safest
+			// to distrust the whole file and fall back to a full replay.
+			return nil, 0, false
+		}
+
+		index[string(key)] = append(index[string(key)], indexEntry{
+			seq:       seq,
+			offset:    recOffset,
+			tombstone: tombstone,
+		})
+		if seq >= maxSeq {
+			maxSeq = seq + 1
+		}
+		offset += n
+	}
+
+	s.keys = sortedKeys(index)
+	return index, maxSeq, true
+}
+
+// appendHintEntries appends one hint entry per record just written at
+// recordOffset in the log, keeping the hint file incrementally up to date so
+// a crash only loses the tail written since the last sync, not the whole
+// cache. When sync is true the hint file is flushed along with the log.
+func (s *Store) appendHintEntries(baseSeq uint64, recordOffset int64, entries []batchEntry, sync bool) error {
+	var buf []byte
+	offset := recordOffset
+	for i, e := range entries {
+		buf = append(buf, encodeHintEntry(e.key, offset, baseSeq+uint64(i), e.tombstone)...)
+		offset += int64(headerSize + len(e.key) + len(e.value))
+	}
+
+	if _, err := s.hintFile.Write(buf); err != nil {
+		return err
+	}
+	if sync {
+		if err := s.hintFile.Sync(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rewriteHint atomically replaces the hint file with one holding one entry
+// per key in the current index, for that key's latest version only, so it
+// doesn't grow without bound across the store's lifetime. It's called whenever the index was just rebuilt from scratch:
+// after a fallback full replay in Open, and at the end of Compact.
+func (s *Store) rewriteHint() error {
+	tmpPath := hintPath(s.filename) + ".tmp"
+	tmpFile, err := os.OpenFile(tmpPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return err
+	}
+
+	var buf []byte
+	for key, versions := range s.index {
+		latest := versions[len(versions)-1]
+		buf = append(buf, encodeHintEntry([]byte(key), latest.offset, latest.seq, latest.tombstone)...)
+	}
+
+	if _, err := tmpFile.Write(buf); err != nil {
+		tmpFile.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmpFile.Sync(); err != nil {
+		tmpFile.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := os.Rename(tmpPath, hintPath(s.filename)); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if s.hintFile != nil {
+		s.hintFile.Close()
+	}
+	hintFile, err := os.OpenFile(hintPath(s.filename), os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		return err
+	}
+	s.hintFile = hintFile
+	return nil
+}
@@ -0,0 +1,209 @@
+package minkv
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestHintFileSpeedsUpReopen(t *testing.T) {
+	store := setupKV(t)
+
+	if err := store.Put([]byte("key1"), []byte("value1")); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if err := store.Put([]byte("key2"), []byte("value2")); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if err := store.Delete([]byte("key1")); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	defer os.Remove("test.db")
+	defer os.Remove("test.db.hint")
+
+	if _, err := os.Stat("test.db.hint"); err != nil {
+		t.Fatalf("expected a hint file to exist: %v", err)
+	}
+
+	reopened, err := Open("test.db")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer func() {
+		if err := reopened.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+	}()
+
+	if _, err := reopened.Get([]byte("key1")); err == nil {
+		t.Error("expected key1 to still be deleted after reopen")
+	}
+	value, err := reopened.Get([]byte("key2"))
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if string(value) != "value2" {
+		t.Errorf("Get = %q, want %q", value, "value2")
+	}
+}
+
+func TestMissingHintFileFallsBackToReplay(t *testing.T) {
+	store := setupKV(t)
+
+	if err := store.Put([]byte("key1"), []byte("value1")); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	defer os.Remove("test.db")
+	defer os.Remove("test.db.hint")
+
+	if err := os.Remove("test.db.hint"); err != nil {
+		t.Fatalf("failed to remove hint file: %v", err)
+	}
+
+	reopened, err := Open("test.db")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer func() {
+		if err := reopened.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+	}()
+
+	value, err := reopened.Get([]byte("key1"))
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if string(value) != "value1" {
+		t.Errorf("Get = %q, want %q", value, "value1")
+	}
+	if _, err := os.Stat("test.db.hint"); err != nil {
+		t.Errorf("expected Open to heal the missing hint file: %v", err)
+	}
+}
+
+func TestStaleHintFileFallsBackToReplay(t *testing.T) {
+	store := setupKV(t)
+
+	if err := store.Put([]byte("key1"), []byte("value1")); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	defer os.Remove("test.db")
+	defer os.Remove("test.db.hint")
+
+	// make the hint file look older than the data file, as if it predates
+	// a write that never made it into the hint (e.g. a crash between the
+	// log append and the hint append).
+	past := time.Now().Add(-time.Hour)
+	if err := os.Chtimes("test.db.hint", past, past); err != nil {
+		t.Fatalf("Chtimes failed: %v", err)
+	}
+
+	reopened, err := Open("test.db")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer func() {
+		if err := reopened.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+	}()
+
+	value, err := reopened.Get([]byte("key1"))
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if string(value) != "value1" {
+		t.Errorf("Get = %q, want %q", value, "value1")
+	}
+}
+
+func TestCorruptHintFileFallsBackToReplay(t *testing.T) {
+	store := setupKV(t)
+
+	if err := store.Put([]byte("key1"), []byte("value1")); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	defer os.Remove("test.db")
+	defer os.Remove("test.db.hint")
+
+	data, err := os.ReadFile("test.db.hint")
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	data[len(data)-1] ^= 0xFF
+	if err := os.WriteFile("test.db.hint", data, 0666); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	reopened, err := Open("test.db")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer func() {
+		if err := reopened.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+	}()
+
+	value, err := reopened.Get([]byte("key1"))
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if string(value) != "value1" {
+		t.Errorf("Get = %q, want %q", value, "value1")
+	}
+}
+
+func TestHintFileOmitsSupersededVersionsAfterCompaction(t *testing.T) {
+	store := setupKV(t)
+	defer cleanupKV(t, store)
+
+	for i := 0; i < 5; i++ {
+		if err := store.Put([]byte("key1"), []byte("value")); err != nil {
+			t.Fatalf("Put failed: %v", err)
+		}
+	}
+	if err := store.Put([]byte("key2"), []byte("value2")); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if err := store.Delete([]byte("key2")); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	if err := store.Compact(); err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+
+	hintInfo, err := os.Stat("test.db.hint")
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+	// one surviving live key and no trace of the deleted one.
+	if hintInfo.Size() > hintEntryFixedSize+int64(len("key1")) {
+		t.Errorf("hint file size = %d, expected just key1's entry", hintInfo.Size())
+	}
+
+	value, err := store.Get([]byte("key1"))
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if string(value) != "value" {
+		t.Errorf("Get = %q, want %q", value, "value")
+	}
+	if _, err := store.Get([]byte("key2")); err == nil {
+		t.Error("expected key2 to remain deleted after compaction")
+	}
+}
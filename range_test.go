@@ -0,0 +1,213 @@
+package minkv
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func collectKeys(t *testing.T, it Iterator) []string {
+	t.Helper()
+
+	var keys []string
+	for it.Next() {
+		record, err := it.Record()
+		if err != nil {
+			t.Fatalf("Record failed: %v", err)
+		}
+		keys = append(keys, string(record.Key))
+	}
+	return keys
+}
+
+func assertKeys(t *testing.T, got []string, want ...string) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+}
+
+func TestRangeReturnsSortedKeysWithinBounds(t *testing.T) {
+	store := setupKV(t)
+	defer cleanupKV(t, store)
+
+	for _, key := range []string{"banana", "apple", "cherry", "date", "fig"} {
+		if err := store.Put([]byte(key), []byte(key)); err != nil {
+			t.Fatalf("Put failed: %v", err)
+		}
+	}
+
+	it := store.Range([]byte("banana"), []byte("date"))
+	assertKeys(t, collectKeys(t, it), "banana", "cherry")
+}
+
+func TestRangeUnboundedStartOrEnd(t *testing.T) {
+	store := setupKV(t)
+	defer cleanupKV(t, store)
+
+	for _, key := range []string{"banana", "apple", "cherry"} {
+		if err := store.Put([]byte(key), []byte(key)); err != nil {
+			t.Fatalf("Put failed: %v", err)
+		}
+	}
+
+	assertKeys(t, collectKeys(t, store.Range(nil, []byte("banana"))), "apple")
+	assertKeys(t, collectKeys(t, store.Range([]byte("banana"), nil)), "banana", "cherry")
+}
+
+func TestSeekStartsAtFirstKeyWithPrefix(t *testing.T) {
+	store := setupKV(t)
+	defer cleanupKV(t, store)
+
+	for _, key := range []string{"apple", "apricot", "banana"} {
+		if err := store.Put([]byte(key), []byte(key)); err != nil {
+			t.Fatalf("Put failed: %v", err)
+		}
+	}
+
+	it := store.Seek([]byte("apricot"))
+	assertKeys(t, collectKeys(t, it), "apricot", "banana")
+}
+
+func TestRangeExcludesDeletedAndSupersededKeys(t *testing.T) {
+	store := setupKV(t)
+	defer cleanupKV(t, store)
+
+	if err := store.Put([]byte("apple"), []byte("old")); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if err := store.Put([]byte("apple"), []byte("new")); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if err := store.Put([]byte("banana"), []byte("value")); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if err := store.Delete([]byte("banana")); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	it := store.Range(nil, nil)
+	var got []string
+	for it.Next() {
+		record, err := it.Record()
+		if err != nil {
+			t.Fatalf("Record failed: %v", err)
+		}
+		got = append(got, string(record.Key)+"="+string(record.Value))
+	}
+	assertKeys(t, got, "apple=new")
+}
+
+func TestIteratorSeekRepositionsWithinRange(t *testing.T) {
+	store := setupKV(t)
+	defer cleanupKV(t, store)
+
+	for _, key := range []string{"apple", "banana", "cherry", "date"} {
+		if err := store.Put([]byte(key), []byte(key)); err != nil {
+			t.Fatalf("Put failed: %v", err)
+		}
+	}
+
+	it, err := store.Iterator()
+	if err != nil {
+		t.Fatalf("Iterator failed: %v", err)
+	}
+
+	if !it.Seek([]byte("banana")) {
+		t.Fatal("expected Seek to find a key >= \"banana\"")
+	}
+	if !it.Valid() {
+		t.Fatal("expected iterator to be valid after a successful Seek")
+	}
+	if string(it.Key()) != "banana" {
+		t.Errorf("Key() = %q, want %q", it.Key(), "banana")
+	}
+	if string(it.Value()) != "banana" {
+		t.Errorf("Value() = %q, want %q", it.Value(), "banana")
+	}
+
+	assertKeys(t, append([]string{string(it.Key())}, collectKeys(t, it)...), "banana", "cherry", "date")
+}
+
+func TestIteratorValidFalseBeforePositioningAndAfterExhaustion(t *testing.T) {
+	store := setupKV(t)
+	defer cleanupKV(t, store)
+
+	if err := store.Put([]byte("apple"), []byte("value")); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	it, err := store.Iterator()
+	if err != nil {
+		t.Fatalf("Iterator failed: %v", err)
+	}
+	if it.Valid() {
+		t.Fatal("expected a freshly created iterator to be invalid")
+	}
+
+	if !it.Next() {
+		t.Fatal("expected Next to find the only key")
+	}
+	if !it.Valid() {
+		t.Fatal("expected the iterator to be valid after Next")
+	}
+
+	if it.Next() {
+		t.Fatal("expected Next to return false once exhausted")
+	}
+	if it.Valid() {
+		t.Fatal("expected an exhausted iterator to be invalid")
+	}
+}
+
+// TestIteratorSurvivesConcurrentWrites guards against rangeIterator reading
+// the index map and the log file without holding the store's lock: a
+// concurrent Put/Delete while Next is walking the keyspace used to be a
+// `fatal error: concurrent map read and map write`, not just a data race.
+// Run with -race.
+func TestIteratorSurvivesConcurrentWrites(t *testing.T) {
+	store := setupKV(t)
+	defer cleanupKV(t, store)
+
+	for i := 0; i < 50; i++ {
+		if err := store.Put([]byte(fmt.Sprintf("key%d", i)), []byte("value")); err != nil {
+			t.Fatalf("Put failed: %v", err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			key := []byte(fmt.Sprintf("key%d", i%50))
+			if err := store.Put(key, []byte("updated")); err != nil {
+				t.Errorf("Put failed: %v", err)
+			}
+			if err := store.Delete(key); err != nil {
+				t.Errorf("Delete failed: %v", err)
+			}
+		}
+	}()
+
+	for i := 0; i < 200; i++ {
+		it, err := store.Iterator()
+		if err != nil {
+			t.Fatalf("Iterator failed: %v", err)
+		}
+		for it.Next() {
+			_, err := it.Record()
+			if err != nil {
+				t.Fatalf("Record failed: %v", err)
+			}
+		}
+	}
+
+	wg.Wait()
+}
@@ -0,0 +1,119 @@
+package minkv
+
+import (
+	"fmt"
+	"math"
+)
+
+// Snapshot is an immutable, point-in-time view of the store, fixed at the
+// sequence number that was current when it was taken. Writes made after a
+// snapshot is taken are invisible to it. Callers must call Release when
+// done so the store knows the snapshot's sequence is no longer needed and a
+// future compaction is free to drop versions only it was keeping alive.
+type Snapshot struct {
+	store *Store
+	seq   uint64
+
+	released bool
+}
+
+// snapshotRegisterHook, when non-nil, is invoked by GetSnapshot after
+// reading nextSeq but before registering the snapshot's sequence, while
+// s.mu is still held. It exists solely so tests can prove a concurrent
+// Compact is blocked from running in that window; production code never
+// sets it.
+var snapshotRegisterHook func()
+
+// GetSnapshot returns a Snapshot reflecting the store's state at the current
+// moment: Get and Iterator on the snapshot will only ever see records
+// written at or before this point, regardless of later writes.
+func (s *Store) GetSnapshot() *Snapshot {
+	// s.mu must stay held across reading nextSeq and registering the
+	// snapshot's sequence: Compact also takes s.mu (exclusively) before
+	// consulting openSnapshots, so releasing it in between would let a
+	// concurrent Compact prune versions this snapshot was about to claim,
+	// before it had a chance to claim them.
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	seq := s.nextSeq - 1
+
+	if snapshotRegisterHook != nil {
+		snapshotRegisterHook()
+	}
+
+	s.snapMu.Lock()
+	s.openSnapshots[seq]++
+	s.snapMu.Unlock()
+
+	return &Snapshot{store: s, seq: seq}
+}
+
+// Release lets the store know this snapshot is no longer in use. It is safe
+// to call more than once; only the first call has an effect.
+func (snap *Snapshot) Release() {
+	if snap.released {
+		return
+	}
+	snap.released = true
+
+	s := snap.store
+	s.snapMu.Lock()
+	defer s.snapMu.Unlock()
+
+	s.openSnapshots[snap.seq]--
+	if s.openSnapshots[snap.seq] == 0 {
+		delete(s.openSnapshots, snap.seq)
+	}
+}
+
+// oldestOpenSnapshotSeq returns the sequence of the oldest snapshot still in
+// use, or math.MaxUint64 if none are open, i.e. the lowest sequence whose
+// superseded versions a compaction must keep around.
+func (s *Store) oldestOpenSnapshotSeq() uint64 {
+	s.snapMu.Lock()
+	defer s.snapMu.Unlock()
+
+	oldest := uint64(math.MaxUint64)
+	for seq := range s.openSnapshots {
+		if seq < oldest {
+			oldest = seq
+		}
+	}
+	return oldest
+}
+
+// Get returns the value of key as of the snapshot's sequence number.
+func (snap *Snapshot) Get(key []byte) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, fmt.Errorf("key cannot be empty")
+	}
+
+	s := snap.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entry, ok := versionAt(s.index[string(key)], snap.seq)
+	if !ok || entry.tombstone {
+		return nil, fmt.Errorf("key not found: %s", key)
+	}
+
+	record, err := s.readRecord(entry.offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read record: %w", err)
+	}
+
+	return record.Value, nil
+}
+
+// Iterator returns an Iterator, in sorted key order, over the keys visible
+// at the snapshot's sequence number.
+func (snap *Snapshot) Iterator() (Iterator, error) {
+	s := snap.store
+	s.mu.RLock()
+	keys := s.keys
+	s.mu.RUnlock()
+
+	seq := snap.seq
+	return &rangeIterator{store: s, keys: keys, pos: -1, snapSeq: &seq}, nil
+}
@@ -0,0 +1,166 @@
+package minkv
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSnapshotIsolatedFromLaterWrites(t *testing.T) {
+	store := setupKV(t)
+	defer cleanupKV(t, store)
+
+	if err := store.Put([]byte("key1"), []byte("value1")); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	snap := store.GetSnapshot()
+	defer snap.Release()
+
+	if err := store.Put([]byte("key1"), []byte("value2")); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if err := store.Put([]byte("key2"), []byte("value3")); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	value, err := snap.Get([]byte("key1"))
+	if err != nil {
+		t.Fatalf("snapshot Get(key1) failed: %v", err)
+	}
+	if string(value) != "value1" {
+		t.Errorf("snapshot Get(key1) = %s, want value1 (pre-snapshot value)", value)
+	}
+
+	if _, err := snap.Get([]byte("key2")); err == nil {
+		t.Error("expected key2, written after the snapshot, to be invisible")
+	}
+
+	current, err := store.Get([]byte("key1"))
+	if err != nil {
+		t.Fatalf("Get(key1) failed: %v", err)
+	}
+	if string(current) != "value2" {
+		t.Errorf("Get(key1) = %s, want value2 (current value)", current)
+	}
+}
+
+func TestSnapshotSeesDeleteAfterSnapshotAsStillPresent(t *testing.T) {
+	store := setupKV(t)
+	defer cleanupKV(t, store)
+
+	if err := store.Put([]byte("key1"), []byte("value1")); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	snap := store.GetSnapshot()
+	defer snap.Release()
+
+	if err := store.Delete([]byte("key1")); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	if _, err := store.Get([]byte("key1")); err == nil {
+		t.Error("expected key1 to be deleted in the live store")
+	}
+
+	value, err := snap.Get([]byte("key1"))
+	if err != nil {
+		t.Fatalf("snapshot Get(key1) failed: %v", err)
+	}
+	if string(value) != "value1" {
+		t.Errorf("snapshot Get(key1) = %s, want value1", value)
+	}
+}
+
+func TestSnapshotIterator(t *testing.T) {
+	store := setupKV(t)
+	defer cleanupKV(t, store)
+
+	if err := store.Put([]byte("key1"), []byte("value1")); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	snap := store.GetSnapshot()
+	defer snap.Release()
+
+	if err := store.Put([]byte("key2"), []byte("value2")); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	it, err := snap.Iterator()
+	if err != nil {
+		t.Fatalf("Iterator failed: %v", err)
+	}
+
+	found := make(map[string]string)
+	for it.Next() {
+		record, err := it.Record()
+		if err != nil {
+			t.Fatalf("Record failed: %v", err)
+		}
+		found[string(record.Key)] = string(record.Value)
+	}
+
+	if len(found) != 1 || found["key1"] != "value1" {
+		t.Errorf("snapshot iterator found %v, want only key1=value1", found)
+	}
+}
+
+func TestSnapshotReleaseIsIdempotent(t *testing.T) {
+	store := setupKV(t)
+	defer cleanupKV(t, store)
+
+	snap := store.GetSnapshot()
+	snap.Release()
+	snap.Release()
+}
+
+// TestGetSnapshotSerializesAgainstConcurrentCompact guards against a race
+// where GetSnapshot reads the current sequence, releases its lock, and only
+// then registers itself in openSnapshots: a concurrent Compact could run
+// to completion in between, see no open snapshots, and prune every key
+// down to its latest version before the new snapshot had a chance to claim
+// the version it was promised. It uses snapshotRegisterHook to deterministically
+// run a concurrent Compact at exactly that point and prove it can't
+// complete until GetSnapshot has registered and returned.
+func TestGetSnapshotSerializesAgainstConcurrentCompact(t *testing.T) {
+	store := setupKV(t)
+	defer cleanupKV(t, store)
+
+	key := []byte("key1")
+	if err := store.Put(key, []byte("v1")); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if err := store.Put(key, []byte("v2")); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	compactDone := make(chan struct{})
+	snapshotRegisterHook = func() {
+		go func() {
+			if err := store.Compact(); err != nil {
+				t.Errorf("Compact failed: %v", err)
+			}
+			close(compactDone)
+		}()
+
+		select {
+		case <-compactDone:
+			t.Error("Compact completed while GetSnapshot still held s.mu; it should have blocked")
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+	defer func() { snapshotRegisterHook = nil }()
+
+	snap := store.GetSnapshot()
+	<-compactDone // Compact was only free to finish once GetSnapshot returned
+
+	value, err := snap.Get(key)
+	if err != nil {
+		t.Fatalf("snapshot Get failed: %v", err)
+	}
+	if string(value) != "v2" {
+		t.Errorf("Get = %q, want %q", value, "v2")
+	}
+	snap.Release()
+}
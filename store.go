@@ -3,102 +3,307 @@ package minkv
 import (
 	"encoding/binary"
 	"fmt"
+	"hash/crc32"
 	"io"
 	"os"
+	"sort"
 	"sync"
-	"time"
 )
 
-// timestamp (4 bytes) + key len (4 bytes) + value len (4 bytes) + tombstone (1 byte)
-const headerSize = 13
-const tombstoneOffset = -1
+// seq (8 bytes) + timestamp (4 bytes) + key len (4 bytes) + value len (4 bytes) + tombstone (1 byte) + crc32 (4 bytes)
+const headerSize = 25
+
+// crc32cTable is the Castagnoli polynomial, the same one used by LevelDB and
+// RocksDB for record-level integrity checks.
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
 
 type Record struct {
 	Key       []byte
 	Value     []byte
+	Seq       uint64
 	Timestamp uint32
 	Tombstone bool
 }
 
+// indexEntry is one version of a key: the sequence it was written at, the
+// offset of its record in the log, and whether it was a delete. A key's
+// versions are appended in increasing seq order as the log is replayed or
+// written, so the current value is always the last entry.
+type indexEntry struct {
+	seq       uint64
+	offset    int64
+	tombstone bool
+}
+
 type Store struct {
 	filename string
 	mu       sync.RWMutex
 	file     *os.File
-	index    map[string]int64
+	hintFile *os.File
+	index    map[string][]indexEntry
+	// keys holds every key in index, sorted, so Range and Seek can binary
+	// search their bounds instead of sorting the whole keyspace per call.
+	// A write that adds a new key replaces keys with a freshly allocated
+	// slice (see insertSortedKey), so an Iterator holding an older keys
+	// slice is never mutated out from under it.
+	keys    []string
+	nextSeq uint64
+	strict  bool
+
+	snapMu        sync.Mutex
+	openSnapshots map[uint64]int
 }
 
-func Open(filename string) (*Store, error) {
+// insertSortedKey returns keys with key inserted at its sorted position, or
+// keys unchanged if it's already present.
+func insertSortedKey(keys []string, key string) []string {
+	i := sort.SearchStrings(keys, key)
+	if i < len(keys) && keys[i] == key {
+		return keys
+	}
+
+	inserted := make([]string, len(keys)+1)
+	copy(inserted, keys[:i])
+	inserted[i] = key
+	copy(inserted[i+1:], keys[i:])
+	return inserted
+}
+
+// sortedKeys returns the sorted keys of index, for rebuilding keys after a
+// full rebuild of index (buildIndex, loadHint, compaction) where it's
+// cheaper to sort once than to insert one key at a time.
+func sortedKeys(index map[string][]indexEntry) []string {
+	keys := make([]string, 0, len(index))
+	for key := range index {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Options configures Open.
+type Options struct {
+	// Strict makes Open fail with an *ErrCorrupted instead of silently
+	// truncating the log when it finds a corrupted or torn trailing
+	// batch. Intended for scripted repair tooling that wants to inspect
+	// or archive the damaged tail itself rather than have Open discard it.
+	Strict bool
+}
+
+// Open opens or creates the log at filename and rebuilds the in-memory
+// index from it, preferring the companion hint file (see hint.go) over a
+// full log replay when it is present, fresh and intact. By default a
+// corrupted or incomplete trailing batch is truncated away (see
+// buildIndex); pass Options{Strict: true} to get an *ErrCorrupted back
+// instead.
+func Open(filename string, opts ...Options) (*Store, error) {
+	var options Options
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+
 	file, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE, 0666)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open file: %w", err)
 	}
+	hintFile, err := os.OpenFile(hintPath(filename), os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to open hint file: %w", err)
+	}
 	store := &Store{
-		filename: filename,
-		file:     file,
-		index:    make(map[string]int64),
+		filename:      filename,
+		file:          file,
+		hintFile:      hintFile,
+		index:         make(map[string][]indexEntry),
+		openSnapshots: make(map[uint64]int),
+		strict:        options.Strict,
 	}
 
-	if err := store.buildIndex(); err != nil {
-		file.Close()
-		return nil, fmt.Errorf("failed to rebuild index: %w", err)
+	if index, nextSeq, ok := store.loadHint(); ok {
+		store.index = index
+		store.nextSeq = nextSeq
+	} else {
+		if err := store.buildIndex(); err != nil {
+			file.Close()
+			hintFile.Close()
+			if _, ok := err.(*ErrCorrupted); ok {
+				return nil, err
+			}
+			return nil, fmt.Errorf("failed to rebuild index: %w", err)
+		}
+		// the hint file was missing, stale or corrupt; heal it from the
+		// index we just rebuilt so the next Open is fast again.
+		if err := store.rewriteHint(); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to rewrite hint file: %w", err)
+		}
+	}
+
+	// sequence 0 is reserved to mean "nothing written yet" so that a
+	// snapshot taken on an empty store sees no keys.
+	if store.nextSeq == 0 {
+		store.nextSeq = 1
 	}
 
 	return store, nil
 }
 
+// buildIndex replays the log, which is a sequence of batch-framed records
+// (see batch.go), rebuilding the in-memory index from the live records it
+// finds. A trailing batch that is short or fails its CRC means the process
+// crashed mid-write; it is truncated away rather than partially applied, so
+// a batch's records are either all visible or none are.
 func (s *Store) buildIndex() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	var offset int64
 	for {
-		record, err := s.readRecord(offset)
-		if err == io.EOF {
-			break
+		header := make([]byte, batchHeaderSize)
+		if err := s.readAt(offset, header); err != nil {
+			if err == io.EOF {
+				break
+			}
+			// short read: a torn write left a partial batch header
+			return s.handleCorruption(offset, fmt.Sprintf("short batch header: %v", err))
 		}
+
+		_, count, wantCRC := decodeBatchHeader(header)
+
+		payloadOffset := offset + batchHeaderSize
+
+		// count comes straight off disk and is untrusted: a torn or
+		// corrupted header could claim billions of records. Bound it
+		// against what could actually fit in the rest of the file before
+		// trusting it to size an allocation.
+		remaining, err := s.remainingBytes(payloadOffset)
 		if err != nil {
-			return fmt.Errorf("failed to read record: %v", err)
+			return s.handleCorruption(offset, fmt.Sprintf("failed to stat file: %v", err))
+		}
+		if int64(count) > remaining/int64(headerSize) {
+			return s.handleCorruption(offset, fmt.Sprintf("batch claims %d records, more than fit in the remaining %d bytes", count, remaining))
 		}
 
-		// mark tombstoned records as deleted
-		if record.Tombstone {
-			s.index[string(record.Key)] = tombstoneOffset
-		} else {
-			s.index[string(record.Key)] = offset
+		records := make([]*Record, 0, count)
+		cursor := payloadOffset
+		for i := uint32(0); i < count; i++ {
+			// readRecord verifies each record's own CRC32C as it reads it,
+			// so a torn write or bit flip anywhere in the batch is caught
+			// here even before the batch-level CRC is checked below.
+			record, err := s.readRecord(cursor)
+			if err != nil {
+				return s.handleCorruption(offset, fmt.Sprintf("record at %d: %v", cursor, err))
+			}
+			records = append(records, record)
+			cursor += int64(headerSize + len(record.Key) + len(record.Value))
+		}
+
+		payload := make([]byte, cursor-payloadOffset)
+		if err := s.readAt(payloadOffset, payload); err != nil {
+			return s.handleCorruption(offset, fmt.Sprintf("short batch payload: %v", err))
 		}
-		offset += int64(headerSize + len(record.Key) + len(record.Value))
+		if crc32.Checksum(payload, crc32cTable) != wantCRC {
+			return s.handleCorruption(offset, "batch payload checksum mismatch")
+		}
+
+		recordOffset := payloadOffset
+		for _, record := range records {
+			key := string(record.Key)
+			s.index[key] = append(s.index[key], indexEntry{
+				seq:       record.Seq,
+				offset:    recordOffset,
+				tombstone: record.Tombstone,
+			})
+			recordOffset += int64(headerSize + len(record.Key) + len(record.Value))
+
+			if record.Seq >= s.nextSeq {
+				s.nextSeq = record.Seq + 1
+			}
+		}
+
+		offset = cursor
 	}
+
+	s.keys = sortedKeys(s.index)
 	return nil
 }
 
+// readAt fills buf with the bytes starting at offset, returning io.EOF only
+// when nothing at all could be read at offset.
+func (s *Store) readAt(offset int64, buf []byte) error {
+	if _, err := s.file.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	_, err := io.ReadFull(s.file, buf)
+	return err
+}
+
+// remainingBytes returns the number of bytes in the file at or after offset,
+// used to sanity-check untrusted length/count fields read from the log
+// before trusting them to size an allocation.
+func (s *Store) remainingBytes(offset int64) (int64, error) {
+	info, err := s.file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	if remaining := info.Size() - offset; remaining > 0 {
+		return remaining, nil
+	}
+	return 0, nil
+}
+
+// handleCorruption is buildIndex's response to a torn write or a bad
+// checksum found at offset: in strict mode it reports it as an
+// *ErrCorrupted for scripted repair tooling to act on, otherwise it
+// truncates the log back to offset, discarding the damaged tail, matching
+// the corruption-recovery behavior of leveldb's log reader.
+func (s *Store) handleCorruption(offset int64, reason string) error {
+	if s.strict {
+		return &ErrCorrupted{Offset: offset, Reason: reason}
+	}
+	return s.file.Truncate(offset)
+}
+
 func (s *Store) Close() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	if s.hintFile != nil {
+		if err := s.hintFile.Close(); err != nil {
+			return err
+		}
+	}
 	if s.file != nil {
 		return s.file.Close()
 	}
 	return nil
 }
 
-func (s *Store) writeRecord(record *Record) (int64, error) {
+// encodeRecord serializes record into buf, which must be at least
+// recordSize(record) bytes long, and returns the number of bytes written.
+// The last 4 bytes of the header are a CRC32C over everything else: the
+// rest of the header plus the key and value.
+func encodeRecord(buf []byte, record *Record) int {
 	totalSize := headerSize + len(record.Key) + len(record.Value)
-	buf := make([]byte, totalSize)
+
+	// serialize seq (8 bytes)
+	binary.BigEndian.PutUint64(buf[0:8], record.Seq)
 
 	// serialize timestamp (4 bytes)
-	binary.BigEndian.PutUint32(buf[0:4], record.Timestamp)
+	binary.BigEndian.PutUint32(buf[8:12], record.Timestamp)
 
 	// serialize key len (4 bytes)
-	binary.BigEndian.PutUint32(buf[4:8], uint32(len(record.Key)))
+	binary.BigEndian.PutUint32(buf[12:16], uint32(len(record.Key)))
 
 	// serialize value len (4 bytes)
-	binary.BigEndian.PutUint32(buf[8:12], uint32(len(record.Value)))
+	binary.BigEndian.PutUint32(buf[16:20], uint32(len(record.Value)))
 
 	// serialize tombstone (1 byte)
 	if record.Tombstone {
-		buf[12] = 1
+		buf[20] = 1
 	} else {
-		buf[12] = 0
+		buf[20] = 0
 	}
 
 	// copy key data
@@ -107,92 +312,100 @@ func (s *Store) writeRecord(record *Record) (int64, error) {
 	// copy value data
 	copy(buf[headerSize+len(record.Key):], record.Value)
 
-	// write record to file
-	offset, err := s.file.Seek(0, io.SeekEnd)
-	if err != nil {
-		return 0, err
-	}
+	// serialize crc32 (4 bytes), covering everything above except itself
+	crc := recordChecksum(buf[0:21], buf[headerSize:totalSize])
+	binary.BigEndian.PutUint32(buf[21:25], crc)
 
-	if _, err := s.file.Write(buf); err != nil {
-		return 0, err
-	}
+	return totalSize
+}
 
-	return offset, nil
+func recordSize(record *Record) int {
+	return headerSize + len(record.Key) + len(record.Value)
 }
 
-func (s *Store) Put(key, value []byte) error {
-	if len(key) == 0 {
-		return fmt.Errorf("key cannot be empty")
+// recordChecksum computes the CRC32C of a record's header fields (excluding
+// the checksum field itself) followed by its key and value.
+func recordChecksum(header, body []byte) uint32 {
+	h := crc32.New(crc32cTable)
+	h.Write(header)
+	h.Write(body)
+	return h.Sum32()
+}
+
+// decodeRecord parses a single record from the start of buf, returning the
+// record along with the number of bytes it occupies. It returns
+// errChecksumMismatch if the record's CRC32C doesn't match its contents.
+func decodeRecord(buf []byte) (*Record, int, error) {
+	if len(buf) < headerSize {
+		return nil, 0, fmt.Errorf("short record header: %d bytes", len(buf))
 	}
 
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	keyLen := binary.BigEndian.Uint32(buf[12:16])
+	valueLen := binary.BigEndian.Uint32(buf[16:20])
 
-	record := &Record{
-		Key:       key,
-		Value:     value,
-		Timestamp: uint32(time.Now().Unix()),
+	total := headerSize + int(keyLen) + int(valueLen)
+	if len(buf) < total {
+		return nil, 0, fmt.Errorf("short record body: need %d bytes, have %d", total, len(buf))
 	}
 
-	offset, err := s.writeRecord(record)
-	if err != nil {
-		return fmt.Errorf("failed to write record: %w", err)
+	wantCRC := binary.BigEndian.Uint32(buf[21:25])
+	if gotCRC := recordChecksum(buf[0:21], buf[headerSize:total]); gotCRC != wantCRC {
+		return nil, 0, fmt.Errorf("%w: want %x, got %x", errChecksumMismatch, wantCRC, gotCRC)
 	}
 
-	s.index[string(key)] = offset
-	return nil
+	record := &Record{
+		Seq:       binary.BigEndian.Uint64(buf[0:8]),
+		Timestamp: binary.BigEndian.Uint32(buf[8:12]),
+		Tombstone: buf[20] == 1,
+		Key:       buf[headerSize : headerSize+int(keyLen)],
+		Value:     buf[headerSize+int(keyLen) : total],
+	}
+	return record, total, nil
 }
 
-func (s *Store) readRecord(offset int64) (*Record, error) {
-	if _, err := s.file.Seek(offset, io.SeekStart); err != nil {
-		return nil, fmt.Errorf("failed to seek: %w", err)
+func (s *Store) Put(key, value []byte) error {
+	if len(key) == 0 {
+		return fmt.Errorf("key cannot be empty")
 	}
 
-	record := &Record{}
-
-	// read timestamp (4 bytes)
-	timestampBuf := make([]byte, 4)
-	if _, err := s.file.Read(timestampBuf); err != nil {
-		return nil, err
-	}
-	record.Timestamp = binary.BigEndian.Uint32(timestampBuf)
+	batch := NewBatch()
+	batch.Put(key, value)
+	return s.Write(batch, false)
+}
 
-	// read key len (4 bytes)
-	keyLenBuf := make([]byte, 4)
-	if _, err := s.file.Read(keyLenBuf); err != nil {
+// readRecord reads and validates the record at offset, verifying its
+// CRC32C against its header, key and value.
+func (s *Store) readRecord(offset int64) (*Record, error) {
+	header := make([]byte, headerSize)
+	if err := s.readAt(offset, header); err != nil {
 		return nil, err
 	}
-	keyLen := binary.BigEndian.Uint32(keyLenBuf)
 
-	// read value len (4 bytes)
-	valueLenBuf := make([]byte, 4)
-	if _, err := s.file.Read(valueLenBuf); err != nil {
-		return nil, err
-	}
-	valueLen := binary.BigEndian.Uint32(valueLenBuf)
+	keyLen := binary.BigEndian.Uint32(header[12:16])
+	valueLen := binary.BigEndian.Uint32(header[16:20])
 
-	// read tombstone (1 byte)
-	tombstoneBuf := make([]byte, 1)
-	if _, err := s.file.Read(tombstoneBuf); err != nil {
+	// keyLen/valueLen come straight off disk and are untrusted: a torn or
+	// corrupted header could claim gigabytes. Bound the body size against
+	// what's actually left in the file before allocating for it.
+	bodyOffset := offset + int64(headerSize)
+	bodyLen := int64(keyLen) + int64(valueLen)
+	remaining, err := s.remainingBytes(bodyOffset)
+	if err != nil {
 		return nil, err
 	}
-	record.Tombstone = tombstoneBuf[0] == 1
-
-	// read key data
-	key := make([]byte, keyLen)
-	if _, err := s.file.Read(key); err != nil {
-		return nil, err
+	if bodyLen > remaining {
+		return nil, fmt.Errorf("record body length %d exceeds %d bytes remaining in file", bodyLen, remaining)
 	}
-	record.Key = key
 
-	// read value data
-	value := make([]byte, valueLen)
-	if _, err := s.file.Read(value); err != nil {
-		return nil, err
+	body := make([]byte, bodyLen)
+	if len(body) > 0 {
+		if err := s.readAt(bodyOffset, body); err != nil {
+			return nil, err
+		}
 	}
-	record.Value = value
 
-	return record, nil
+	record, _, err := decodeRecord(append(header, body...))
+	return record, err
 }
 
 func (s *Store) Get(key []byte) ([]byte, error) {
@@ -203,12 +416,17 @@ func (s *Store) Get(key []byte) ([]byte, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	offset, exists := s.index[string(key)]
-	if !exists || offset == tombstoneOffset {
+	versions := s.index[string(key)]
+	if len(versions) == 0 {
 		return nil, fmt.Errorf("key not found: %s", key)
 	}
 
-	record, err := s.readRecord(offset)
+	latest := versions[len(versions)-1]
+	if latest.tombstone {
+		return nil, fmt.Errorf("key not found: %s", key)
+	}
+
+	record, err := s.readRecord(latest.offset)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read record: %w", err)
 	}
@@ -216,87 +434,187 @@ func (s *Store) Get(key []byte) ([]byte, error) {
 	return record.Value, nil
 }
 
-func (s *Store) Delete(key []byte) error {
-	if len(key) == 0 {
-		return fmt.Errorf("key cannot be empty")
-	}
+// versionAt returns the rightmost version of a key with seq <= the given
+// sequence number, i.e. the value that was current as of that sequence.
+func versionAt(versions []indexEntry, seq uint64) (indexEntry, bool) {
+	entry, _, ok := versionAtIndex(versions, seq)
+	return entry, ok
+}
 
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	record := &Record{
-		Key:       key,
-		Tombstone: true,
-		Timestamp: uint32(time.Now().Unix()),
+// versionAtIndex is versionAt, additionally returning the position of the
+// matching version within versions.
+func versionAtIndex(versions []indexEntry, seq uint64) (indexEntry, int, bool) {
+	lo, hi := 0, len(versions)
+	for lo < hi {
+		mid := (lo + hi) / 2
+		if versions[mid].seq <= seq {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+	if lo == 0 {
+		return indexEntry{}, 0, false
 	}
+	return versions[lo-1], lo - 1, true
+}
 
-	if _, err := s.writeRecord(record); err != nil {
-		return fmt.Errorf("failed to write record: %w", err)
+func (s *Store) Delete(key []byte) error {
+	if len(key) == 0 {
+		return fmt.Errorf("key cannot be empty")
 	}
 
-	s.index[string(key)] = tombstoneOffset
-	return nil
+	batch := NewBatch()
+	batch.Delete(key)
+	return s.Write(batch, false)
 }
 
+// Iterator walks a range of the keyspace in sorted byte order, with the
+// ergonomics of goleveldb's iterator so callers can page through results.
+// Obtain one from Store.Iterator, Store.Range, Store.Seek or
+// Snapshot.Iterator.
 type Iterator interface {
+	// Next advances to the next key in range, returning false once the
+	// iterator is exhausted or a read error is hit (see Record).
 	Next() bool
+	// Seek repositions the iterator to the first key within its range
+	// that is >= key, returning false if none exists.
+	Seek(key []byte) bool
+	// Key and Value return the current record's key and value; both are
+	// nil if Valid reports false.
+	Key() []byte
+	Value() []byte
+	// Valid reports whether the iterator is currently positioned on a
+	// record.
+	Valid() bool
+	// Record returns the record the iterator is currently positioned on,
+	// or the first error encountered while reading one.
 	Record() (*Record, error)
 }
 
-type storeIterator struct {
-	store    *Store
-	record   *Record
-	offset   int64
-	fileSize int64
-	err      error
+// rangeIterator walks the sorted in-memory key index directly rather than
+// the log, so it can start anywhere in the keyspace without scanning
+// everything before it.
+type rangeIterator struct {
+	store *Store
+	keys  []string // sorted keys within the iterator's range, fixed at creation
+	pos   int      // index into keys of the current record, or -1 before positioning
+
+	record *Record
+	err    error
+
+	// snapSeq, when non-nil, restricts iteration to the version of each
+	// key that was current at that sequence number (see Snapshot.Iterator).
+	snapSeq *uint64
 }
 
+// Iterator returns an Iterator over every live key in the store, in sorted
+// order. It is equivalent to Range(nil, nil).
 func (s *Store) Iterator() (Iterator, error) {
+	return s.Range(nil, nil), nil
+}
+
+// Range returns an Iterator over live keys in [start, end), in sorted byte
+// order. A nil start begins at the first key; a nil end has no upper
+// bound.
+func (s *Store) Range(start, end []byte) Iterator {
 	s.mu.RLock()
-	defer s.mu.RUnlock()
+	keys := s.keys
+	s.mu.RUnlock()
 
-	fileInfo, err := s.file.Stat()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get file info: %w", err)
+	lo := 0
+	if start != nil {
+		lo = sort.SearchStrings(keys, string(start))
+	}
+	hi := len(keys)
+	if end != nil {
+		hi = sort.SearchStrings(keys, string(end))
+	}
+	if hi < lo {
+		hi = lo
 	}
 
-	return &storeIterator{
-		store:    s,
-		offset:   0,
-		fileSize: fileInfo.Size(),
-	}, nil
+	return &rangeIterator{store: s, keys: keys[lo:hi], pos: -1}
 }
 
-func (it *storeIterator) Next() bool {
-	for it.offset < it.fileSize {
-		record, err := it.store.readRecord(it.offset)
-		if err != nil {
-			it.err = fmt.Errorf("failed to read record at offset %d: %w", it.offset, err)
-			return false
-		}
+// Seek returns an Iterator starting at the first key >= prefix and
+// continuing to the end of the keyspace; equivalent to Range(prefix, nil).
+func (s *Store) Seek(prefix []byte) Iterator {
+	return s.Range(prefix, nil)
+}
 
-		recordSize := headerSize + len(record.Key) + len(record.Value)
+// currentVersion returns the version of key visible to it: the version
+// current as of snapSeq if set, otherwise the latest one.
+func (it *rangeIterator) currentVersion(key string) (indexEntry, bool) {
+	versions := it.store.index[key]
+	if len(versions) == 0 {
+		return indexEntry{}, false
+	}
+	if it.snapSeq != nil {
+		return versionAt(versions, *it.snapSeq)
+	}
+	return versions[len(versions)-1], true
+}
 
-		// skip tombstoned record
-		if record.Tombstone {
-			it.offset += int64(recordSize)
-			continue
+func (it *rangeIterator) Next() bool {
+	for it.pos+1 < len(it.keys) {
+		it.pos++
+		key := it.keys[it.pos]
+
+		// the index and the log file it points into can both change out
+		// from under us between keys (a concurrent Put, Delete or
+		// Compact), so take the same lock Get does around every lookup
+		// rather than just once when the iterator was created.
+		it.store.mu.RLock()
+		entry, ok := it.currentVersion(key)
+		var record *Record
+		var err error
+		if ok && !entry.tombstone {
+			record, err = it.store.readRecord(entry.offset)
 		}
+		it.store.mu.RUnlock()
 
-		off, exists := it.store.index[string(record.Key)]
-		if exists && off == it.offset {
-			it.record = record
-			it.offset += int64(recordSize)
-			return true
+		if !ok || entry.tombstone {
+			continue
+		}
+		if err != nil {
+			it.err = fmt.Errorf("failed to read record for key %q: %w", key, err)
+			it.record = nil
+			return false
 		}
 
-		it.offset += int64(recordSize)
+		it.record = record
+		return true
 	}
 
 	it.record = nil
 	return false
 }
 
-func (it *storeIterator) Record() (*Record, error) {
+func (it *rangeIterator) Seek(key []byte) bool {
+	it.pos = sort.SearchStrings(it.keys, string(key)) - 1
+	return it.Next()
+}
+
+func (it *rangeIterator) Key() []byte {
+	if it.record == nil {
+		return nil
+	}
+	return it.record.Key
+}
+
+func (it *rangeIterator) Value() []byte {
+	if it.record == nil {
+		return nil
+	}
+	return it.record.Value
+}
+
+func (it *rangeIterator) Valid() bool {
+	return it.record != nil
+}
+
+func (it *rangeIterator) Record() (*Record, error) {
 	if it.err != nil {
 		return nil, it.err
 	}
@@ -23,6 +23,9 @@ func cleanupKV(t *testing.T, s *Store) {
 	if err := os.Remove("test.db"); err != nil {
 		t.Fatalf("failed to remove test.db: %v", err)
 	}
+	if err := os.Remove("test.db.hint"); err != nil && !os.IsNotExist(err) {
+		t.Fatalf("failed to remove test.db.hint: %v", err)
+	}
 }
 
 func TestPutAndGet(t *testing.T) {